@@ -0,0 +1,326 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// cacheBinaries is the set of binaries minikube downloads and caches locally
+// per Kubernetes version.
+var cacheBinaries = []string{"kubelet", "kubeadm"}
+
+// downloadRetries is how many mirrors/backoff rounds maybeDownloadAndCache
+// will try before giving up on a binary.
+const downloadRetries = 4
+
+// lockWait is how long to wait between attempts to acquire a cache lockfile.
+const lockWait = 500 * time.Millisecond
+
+// maybeDownloadAndCache downloads binary at version into the local cache if
+// it isn't already present, verifying its digest either way, and returns the
+// path to the cached file. The cache is content-addressed: the verified file
+// lives under cache/sha256/<hex digest>, and cache/<version>/<binary> is a
+// symlink to it, so two versions that happen to ship byte-identical binaries
+// share one copy on disk.
+func maybeDownloadAndCache(binary, version string) (string, error) {
+	versionDir := constants.MakeMiniPath("cache", version)
+	linkPath := path.Join(versionDir, binary)
+
+	if target, err := verifiedCacheTarget(linkPath, binary, version); err == nil {
+		return target, nil
+	}
+
+	unlock, err := lockCacheEntry(versionDir, binary)
+	if err != nil {
+		return "", errors.Wrapf(err, "locking cache entry for %s %s", binary, version)
+	}
+	defer unlock()
+
+	// Another process may have finished the download while we waited for the lock.
+	if target, err := verifiedCacheTarget(linkPath, binary, version); err == nil {
+		return target, nil
+	}
+
+	if err := os.MkdirAll(versionDir, 0777); err != nil {
+		return "", errors.Wrapf(err, "mkdir %s", versionDir)
+	}
+
+	contentDir := constants.MakeMiniPath("cache", "sha256")
+	if err := os.MkdirAll(contentDir, 0777); err != nil {
+		return "", errors.Wrapf(err, "mkdir %s", contentDir)
+	}
+
+	tmp := linkPath + ".download"
+	digest, err := downloadWithMirrorsAndResume(releaseMirrors(binary, version), tmp)
+	if err != nil {
+		return "", errors.Wrapf(err, "downloading %s %s", binary, version)
+	}
+
+	contentPath := path.Join(contentDir, digest)
+	if err := os.Rename(tmp, contentPath); err != nil {
+		return "", errors.Wrapf(err, "moving %s into content cache", tmp)
+	}
+	if err := os.Chmod(contentPath, 0755); err != nil {
+		return "", errors.Wrapf(err, "chmod %s", contentPath)
+	}
+
+	os.Remove(linkPath)
+	if err := os.Symlink(contentPath, linkPath); err != nil {
+		return "", errors.Wrapf(err, "symlinking %s to %s", linkPath, contentPath)
+	}
+
+	return linkPath, nil
+}
+
+// PrefetchBinaries downloads and caches every binary minikube needs for each
+// of the given Kubernetes versions, so an air-gapped environment can
+// pre-populate its cache before running `minikube start` without network
+// access.
+func PrefetchBinaries(versions []string) error {
+	for _, version := range versions {
+		for _, binary := range cacheBinaries {
+			if _, err := maybeDownloadAndCache(binary, version); err != nil {
+				return errors.Wrapf(err, "prefetching %s %s", binary, version)
+			}
+		}
+	}
+	return nil
+}
+
+// verifiedCacheTarget returns the resolved, digest-verified cache file for
+// linkPath, or an error if it's missing or has been tampered with.
+func verifiedCacheTarget(linkPath, binary, version string) (string, error) {
+	target, err := filepath.EvalSymlinks(linkPath)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyDigestFilename(target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// verifyDigestFilename re-hashes a cache/sha256/<hex> file and confirms its
+// contents still match its filename, guarding against a truncated or
+// corrupted cache entry being served up as good.
+func verifyDigestFilename(target string) error {
+	wantDigest := filepath.Base(target)
+	f, err := os.Open(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if fmt.Sprintf("%x", h.Sum(nil)) != wantDigest {
+		return errors.Errorf("cache entry %s failed sha256 verification", target)
+	}
+	return nil
+}
+
+// mirrorURL pairs a download URL with the digest minikube expects it to
+// produce, and the hash algorithm that digest was published under.
+type mirrorURL struct {
+	url          string
+	checksum     string
+	checksumHash func() hash.Hash
+}
+
+// releaseMirrors builds the ordered list of URLs to try for binary at
+// version. constants.ReleaseMirrors, when set, is tried ahead of the
+// upstream Kubernetes release URL so users behind a slow or blocked path to
+// dl.k8s.io can configure a closer mirror.
+func releaseMirrors(binary, version string) []mirrorURL {
+	sha256sum := constants.GetKubernetesReleaseURLSha256(binary, version)
+	sha1sum := constants.GetKubernetesReleaseURLSha1(binary, version)
+
+	var mirrors []mirrorURL
+	for _, base := range constants.ReleaseMirrors {
+		mirrors = append(mirrors, mirrorURL{
+			url:          constants.GetKubernetesReleaseURLWithMirror(base, binary, version),
+			checksum:     sha256sum,
+			checksumHash: sha256.New,
+		})
+	}
+
+	m := mirrorURL{url: constants.GetKubernetesReleaseURL(binary, version)}
+	if sha256sum != "" {
+		m.checksum, m.checksumHash = sha256sum, sha256.New
+	} else {
+		m.checksum, m.checksumHash = sha1sum, sha1.New
+	}
+	return append(mirrors, m)
+}
+
+// downloadWithMirrorsAndResume downloads the first mirror that succeeds to
+// dest, resuming a previous partial attempt via an HTTP Range request
+// instead of restarting from zero, and backing off exponentially between
+// attempts. It returns the verified sha256 digest of the downloaded file.
+func downloadWithMirrorsAndResume(mirrors []mirrorURL, dest string) (string, error) {
+	var lastErr error
+	backoff := time.Second
+	for attempt := 0; attempt < downloadRetries; attempt++ {
+		for _, m := range mirrors {
+			digest, err := downloadWithResume(m, dest)
+			if err == nil {
+				return digest, nil
+			}
+			glog.Warningf("download from %s failed: %v", m.url, err)
+			lastErr = err
+		}
+		if attempt < downloadRetries-1 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return "", lastErr
+}
+
+// downloadWithResume downloads m.url to dest, appending to any partial file
+// already at dest via a Range request, and verifies the complete file
+// against m.checksum once the download finishes.
+func downloadWithResume(m mirrorURL, dest string) (string, error) {
+	var startAt int64
+	if fi, err := os.Stat(dest); err == nil {
+		startAt = fi.Size()
+	}
+
+	req, err := http.NewRequest("GET", m.url, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "building request")
+	}
+	if startAt > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", startAt))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "requesting download")
+	}
+	defer resp.Body.Close()
+
+	// Opened read-write: verifyDownload reads the whole file back to hash it.
+	flags := os.O_CREATE | os.O_RDWR
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// The server ignored our Range header and is sending the whole
+		// file again, so start over rather than appending onto stale data.
+		flags |= os.O_TRUNC
+	default:
+		return "", errors.Errorf("unexpected status %s downloading %s", resp.Status, m.url)
+	}
+
+	f, err := os.OpenFile(dest, flags, 0644)
+	if err != nil {
+		return "", errors.Wrapf(err, "opening %s", dest)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return "", errors.Wrap(err, "writing downloaded content")
+	}
+
+	digest, err := verifyDownload(f, m)
+	if err != nil {
+		// The file on disk doesn't match what it's supposed to, most often
+		// because of a checksum mismatch. Remove it rather than leaving it
+		// in place: a Range request against a file that's already at (or
+		// past) the correct final size would otherwise get a 416/unexpected
+		// status on the next attempt and fail without ever re-downloading,
+		// poisoning this cache entry for every future invocation too.
+		f.Close()
+		os.Remove(dest)
+		return "", err
+	}
+	return digest, nil
+}
+
+// verifyDownload hashes the full contents of f (both the resumed portion and
+// what was just appended) and confirms the result matches m.checksum,
+// falling back to treating the sha256 hex digest as the cache key when no
+// checksum was published to check against.
+func verifyDownload(f *os.File, m mirrorURL) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", errors.Wrap(err, "seeking to verify download")
+	}
+
+	sha256Hash := sha256.New()
+	var checkHash hash.Hash
+	w := io.Writer(sha256Hash)
+	if m.checksumHash != nil && m.checksum != "" {
+		checkHash = m.checksumHash()
+		w = io.MultiWriter(sha256Hash, checkHash)
+	}
+
+	if _, err := io.Copy(w, f); err != nil {
+		return "", errors.Wrap(err, "hashing downloaded content")
+	}
+
+	if checkHash != nil {
+		if got := fmt.Sprintf("%x", checkHash.Sum(nil)); got != m.checksum {
+			return "", errors.Errorf("checksum mismatch for %s: got %s, want %s", m.url, got, m.checksum)
+		}
+	}
+	return fmt.Sprintf("%x", sha256Hash.Sum(nil)), nil
+}
+
+// lockCacheEntry acquires an exclusive, filesystem-based lock for
+// <dir>/<binary>, so concurrent `minikube start` runs don't clobber the same
+// in-progress download. The returned function releases the lock.
+func lockCacheEntry(dir, binary string) (func(), error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	lockPath := path.Join(dir, "."+binary+".lock")
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		// Stale lock left behind by a crashed process; steal it rather
+		// than waiting forever.
+		if fi, statErr := os.Stat(lockPath); statErr == nil && time.Since(fi.ModTime()) > 10*time.Minute {
+			os.Remove(lockPath)
+			continue
+		}
+		time.Sleep(lockWait)
+	}
+}
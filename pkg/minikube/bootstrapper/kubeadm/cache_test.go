@@ -0,0 +1,95 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyDownloadChecksumMismatch(t *testing.T) {
+	f, err := ioutil.TempFile("", "cache-test")
+	if err != nil {
+		t.Fatalf("TempFile: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.WriteString("hello world"); err != nil {
+		t.Fatalf("WriteString: %v", err)
+	}
+
+	m := mirrorURL{url: "http://example.invalid/bin", checksum: "deadbeef", checksumHash: sha256.New}
+	if _, err := verifyDownload(f, m); err == nil {
+		t.Fatal("verifyDownload: expected a checksum mismatch error, got nil")
+	}
+}
+
+func TestVerifyDownloadNoChecksumReturnsSha256Digest(t *testing.T) {
+	f, err := ioutil.TempFile("", "cache-test")
+	if err != nil {
+		t.Fatalf("TempFile: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	data := "hello world"
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatalf("WriteString: %v", err)
+	}
+
+	digest, err := verifyDownload(f, mirrorURL{url: "http://example.invalid/bin"})
+	if err != nil {
+		t.Fatalf("verifyDownload: %v", err)
+	}
+	want := fmt.Sprintf("%x", sha256.Sum256([]byte(data)))
+	if digest != want {
+		t.Errorf("verifyDownload digest = %q, want %q", digest, want)
+	}
+}
+
+func TestDownloadWithResumeRemovesDestOnChecksumMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "payload")
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "cache-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "binary")
+
+	m := mirrorURL{
+		url:          srv.URL,
+		checksum:     "0000000000000000000000000000000000000000000000000000000000000000",
+		checksumHash: sha256.New,
+	}
+	if _, err := downloadWithResume(m, dest); err == nil {
+		t.Fatal("downloadWithResume: expected a checksum mismatch error, got nil")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("downloadWithResume: dest still exists after checksum mismatch (stat err = %v); "+
+			"a retry would send a stale Range request instead of re-downloading", err)
+	}
+}
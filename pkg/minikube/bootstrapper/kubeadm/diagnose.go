@@ -0,0 +1,219 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/util"
+)
+
+// Severity indicates how serious a diagnostic finding is.
+type Severity string
+
+const (
+	// Error indicates the node is very likely to fail to start or run a
+	// cluster until the issue is fixed.
+	Error Severity = "ERROR"
+	// Warning indicates a condition that may cause problems, but isn't
+	// known to be fatal on its own.
+	Warning Severity = "WARNING"
+	// OK indicates the check passed.
+	OK Severity = "OK"
+)
+
+// DiagnosticResult is the outcome of a single preflight-style check.
+type DiagnosticResult struct {
+	Name        string   `json:"name"`
+	Severity    Severity `json:"severity"`
+	Message     string   `json:"message"`
+	Remediation string   `json:"remediation,omitempty"`
+}
+
+// diagnosticCheck is a single node-side check run as part of Diagnose.
+type diagnosticCheck struct {
+	name string
+	run  func(k *KubeadmBootstrapper, k8s config.KubernetesConfig) DiagnosticResult
+}
+
+// diagnosticChecks is the battery of checks Diagnose runs, roughly mirroring
+// kubeadm's own preflight system but surfaced as inspectable results instead
+// of an all-or-nothing error.
+var diagnosticChecks = []diagnosticCheck{
+	{"kernel-modules", checkKernelModules},
+	{"sysctls", checkSysctls},
+	{"swap", checkSwap},
+	{"cri-socket", checkCRISocket},
+	{"required-ports", checkRequiredPorts},
+	{"cgroup-driver", checkCgroupDriver},
+	{"time-drift", checkTimeDrift},
+}
+
+// Diagnose runs a battery of node-side checks analogous to kubeadm's
+// preflight system and returns one DiagnosticResult per check. It never
+// returns an error for a failed check; failures are reported as results
+// with Severity Error or Warning so `minikube diagnose` can print them and
+// CI can consume them as JSON.
+func (k *KubeadmBootstrapper) Diagnose(k8s config.KubernetesConfig) ([]DiagnosticResult, error) {
+	results := make([]DiagnosticResult, 0, len(diagnosticChecks))
+	for _, c := range diagnosticChecks {
+		results = append(results, c.run(k, k8s))
+	}
+	return results, nil
+}
+
+func checkKernelModules(k *KubeadmBootstrapper, k8s config.KubernetesConfig) DiagnosticResult {
+	modules := []string{"br_netfilter", "overlay"}
+	var missing []string
+	for _, m := range modules {
+		if out, err := k.c.CombinedOutput(fmt.Sprintf("lsmod | grep -w %s", m)); err != nil || strings.TrimSpace(out) == "" {
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) == 0 {
+		return DiagnosticResult{Name: "kernel-modules", Severity: OK, Message: "required kernel modules are loaded"}
+	}
+	return DiagnosticResult{
+		Name:        "kernel-modules",
+		Severity:    Error,
+		Message:     fmt.Sprintf("kernel modules not loaded: %s", strings.Join(missing, ", ")),
+		Remediation: fmt.Sprintf("run `sudo modprobe %s`", strings.Join(missing, " ")),
+	}
+}
+
+func checkSysctls(k *KubeadmBootstrapper, k8s config.KubernetesConfig) DiagnosticResult {
+	wanted := map[string]string{
+		"net.ipv4.ip_forward":                "1",
+		"net.bridge.bridge-nf-call-iptables": "1",
+	}
+	var bad []string
+	for key, want := range wanted {
+		out, err := k.c.CombinedOutput(fmt.Sprintf("sudo sysctl -n %s", key))
+		if err != nil || strings.TrimSpace(out) != want {
+			bad = append(bad, key)
+		}
+	}
+	if len(bad) == 0 {
+		return DiagnosticResult{Name: "sysctls", Severity: OK, Message: "required sysctls are set"}
+	}
+	return DiagnosticResult{
+		Name:        "sysctls",
+		Severity:    Error,
+		Message:     fmt.Sprintf("sysctls not set to the required value: %s", strings.Join(bad, ", ")),
+		Remediation: "set the listed sysctls to 1, e.g. `sudo sysctl -w net.ipv4.ip_forward=1`",
+	}
+}
+
+func checkSwap(k *KubeadmBootstrapper, k8s config.KubernetesConfig) DiagnosticResult {
+	out, err := k.c.CombinedOutput("sudo swapon --summary")
+	if err != nil {
+		return DiagnosticResult{Name: "swap", Severity: Warning, Message: "unable to determine swap status"}
+	}
+	if strings.TrimSpace(out) != "" {
+		return DiagnosticResult{
+			Name:        "swap",
+			Severity:    Error,
+			Message:     "swap is enabled, which kubelet refuses to run with by default",
+			Remediation: "run `sudo swapoff -a` and remove the swap entry from /etc/fstab",
+		}
+	}
+	return DiagnosticResult{Name: "swap", Severity: OK, Message: "swap is disabled"}
+}
+
+func checkCRISocket(k *KubeadmBootstrapper, k8s config.KubernetesConfig) DiagnosticResult {
+	socket := GetCRISocket(k8s.CRISocket, k8s.ContainerRuntime)
+	if socket == "" {
+		return DiagnosticResult{Name: "cri-socket", Severity: OK, Message: "using the default container runtime, no CRI socket to check"}
+	}
+	if err := k.c.Run(fmt.Sprintf("test -S %s", socket)); err != nil {
+		return DiagnosticResult{
+			Name:        "cri-socket",
+			Severity:    Error,
+			Message:     fmt.Sprintf("CRI socket %s is not reachable", socket),
+			Remediation: fmt.Sprintf("confirm the %s container runtime is installed and running", k8s.ContainerRuntime),
+		}
+	}
+	return DiagnosticResult{Name: "cri-socket", Severity: OK, Message: fmt.Sprintf("CRI socket %s is reachable", socket)}
+}
+
+func checkRequiredPorts(k *KubeadmBootstrapper, k8s config.KubernetesConfig) DiagnosticResult {
+	apiServerPort := k8s.NodePort
+	if apiServerPort <= 0 {
+		apiServerPort = util.APIServerPort
+	}
+	ports := []int{10250, 10251, 10252, 2379, 2380, apiServerPort}
+
+	var inUse []int
+	for _, p := range ports {
+		cmd := fmt.Sprintf("sudo sh -c \"ss -ltn 2>/dev/null | grep -q ':%d '\"", p)
+		if err := k.c.Run(cmd); err == nil {
+			inUse = append(inUse, p)
+		}
+	}
+	if len(inUse) == 0 {
+		return DiagnosticResult{Name: "required-ports", Severity: OK, Message: "all required ports are free"}
+	}
+	return DiagnosticResult{
+		Name:        "required-ports",
+		Severity:    Error,
+		Message:     fmt.Sprintf("ports already in use: %v", inUse),
+		Remediation: "stop whatever is bound to the listed ports before starting the cluster",
+	}
+}
+
+func checkCgroupDriver(k *KubeadmBootstrapper, k8s config.KubernetesConfig) DiagnosticResult {
+	kubeletOut, kErr := k.c.CombinedOutput(`sudo cat /var/lib/kubelet/config.yaml 2>/dev/null | grep cgroupDriver | awk '{print $2}'`)
+	kubeletDriver := strings.TrimSpace(kubeletOut)
+
+	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Socket: k8s.CRISocket})
+	if err != nil {
+		return DiagnosticResult{Name: "cgroup-driver", Severity: Warning, Message: fmt.Sprintf("unable to determine container runtime: %v", err)}
+	}
+	runtimeDriver, rErr := r.CgroupDriver(k.c)
+
+	if kErr != nil || rErr != nil || kubeletDriver == "" || runtimeDriver == "" {
+		return DiagnosticResult{Name: "cgroup-driver", Severity: Warning, Message: "unable to determine cgroup driver for kubelet and/or the container runtime"}
+	}
+	if kubeletDriver != runtimeDriver {
+		return DiagnosticResult{
+			Name:        "cgroup-driver",
+			Severity:    Error,
+			Message:     fmt.Sprintf("kubelet cgroup driver %q does not match container runtime cgroup driver %q", kubeletDriver, runtimeDriver),
+			Remediation: "set --cgroup-driver on the kubelet to match the container runtime, then restart kubelet",
+		}
+	}
+	return DiagnosticResult{Name: "cgroup-driver", Severity: OK, Message: fmt.Sprintf("kubelet and container runtime both use the %q cgroup driver", kubeletDriver)}
+}
+
+func checkTimeDrift(k *KubeadmBootstrapper, k8s config.KubernetesConfig) DiagnosticResult {
+	out, err := k.c.CombinedOutput("timedatectl show -p NTPSynchronized --value 2>/dev/null")
+	if err != nil {
+		return DiagnosticResult{Name: "time-drift", Severity: Warning, Message: "unable to determine NTP sync status"}
+	}
+	if strings.TrimSpace(out) != "yes" {
+		return DiagnosticResult{
+			Name:        "time-drift",
+			Severity:    Warning,
+			Message:     "clock is not synchronized against NTP, which can cause TLS certificate validation failures",
+			Remediation: "run `sudo timedatectl set-ntp true` or install an NTP client",
+		}
+	}
+	return DiagnosticResult{Name: "time-drift", Severity: OK, Message: "clock is synchronized against NTP"}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/config"
+)
+
+// portCheckRunner answers the `ss -ltn ... grep ':<port> '` checks
+// checkRequiredPorts runs as if only the ports in portsInUse were bound.
+type portCheckRunner struct {
+	portsInUse map[int]bool
+}
+
+func (r *portCheckRunner) Run(cmd string) error {
+	for port, inUse := range r.portsInUse {
+		if inUse && strings.Contains(cmd, fmt.Sprintf(":%d '", port)) {
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (r *portCheckRunner) CombinedOutput(cmd string) (string, error)      { return "", nil }
+func (r *portCheckRunner) CombinedOutputTo(cmd string, w io.Writer) error { return nil }
+func (r *portCheckRunner) Copy(file assets.CopyableFile) error            { return nil }
+
+func TestCheckRequiredPortsUsesConfiguredNodePort(t *testing.T) {
+	k := &KubeadmBootstrapper{c: &portCheckRunner{portsInUse: map[int]bool{9443: true}}}
+
+	result := checkRequiredPorts(k, config.KubernetesConfig{NodePort: 9443})
+	if result.Severity != Error {
+		t.Fatalf("checkRequiredPorts with NodePort 9443 in use: severity = %v, want %v", result.Severity, Error)
+	}
+
+	result = checkRequiredPorts(k, config.KubernetesConfig{NodePort: 6443})
+	if result.Severity != OK {
+		t.Errorf("checkRequiredPorts with a free, non-default NodePort: severity = %v, want %v (message: %s)", result.Severity, OK, result.Message)
+	}
+}
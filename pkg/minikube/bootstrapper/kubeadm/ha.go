@@ -0,0 +1,129 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"bytes"
+	"net"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/config"
+)
+
+// haManifestPath is where UpdateCluster drops the static pod manifest for
+// the HA load balancer, so the kubelet picks it up the same way it does any
+// other static pod.
+const haManifestPath = "/etc/kubernetes/manifests/minikube-lb.yaml"
+
+// haLoadBalancerImage is the load balancer proxying HAEndpoint to the
+// apiserver backends. haproxy is a real, published image rather than a
+// bespoke minikube binary, so the manifest is actually pullable.
+const haLoadBalancerImage = "haproxy:2.0-alpine"
+
+// haVIPImage provides the `ip` binary the init container uses to bind the
+// HA VIP to loopback before haproxy starts listening on it.
+const haVIPImage = "busybox:1.31"
+
+// haLoadBalancerManifest is a static pod that binds the HA VIP to loopback
+// and runs haproxy in front of the apiserver backends. It stands in for an
+// external keepalived/haproxy pair, giving minikube a single-node way to
+// prototype the multi-apiserver control-plane-endpoint topology; a future
+// multi-node minikube can swap this out for a real load balancer without
+// touching anything that depends on HAEndpoint being reachable.
+var haLoadBalancerManifest = template.Must(template.New("haLoadBalancerManifest").Parse(`apiVersion: v1
+kind: Pod
+metadata:
+  name: minikube-lb
+  namespace: kube-system
+  labels:
+    component: minikube-lb
+spec:
+  hostNetwork: true
+  initContainers:
+  - name: minikube-lb-vip
+    image: {{.VIPImage}}
+    command: ["sh", "-c", "ip addr replace {{.VIP}}/32 dev lo"]
+    securityContext:
+      capabilities:
+        add: ["NET_ADMIN"]
+  containers:
+  - name: minikube-lb
+    image: {{.Image}}
+    command:
+    - sh
+    - -c
+    - |
+      cat > /usr/local/etc/haproxy/haproxy.cfg <<'HAPROXY_EOF'
+      global
+          daemon
+      defaults
+          mode tcp
+          timeout connect 5s
+          timeout client 30s
+          timeout server 30s
+      frontend apiserver
+          bind {{.VIP}}:{{.Port}}
+          default_backend apiservers
+      backend apiservers
+          option httpchk GET /healthz
+          http-check expect status 200
+          {{range $i, $b := .Backends}}server apiserver{{$i}} {{$b}} check check-ssl verify none
+          {{end}}
+      HAPROXY_EOF
+      exec haproxy -f /usr/local/etc/haproxy/haproxy.cfg
+    securityContext:
+      capabilities:
+        add: ["NET_ADMIN"]
+`))
+
+type haLoadBalancerOpts struct {
+	Image    string
+	VIPImage string
+	VIP      string
+	Port     string
+	Backends []string
+}
+
+// generateHALoadBalancerManifest renders the static pod manifest for the HA
+// load balancer bound to k8s.HAEndpoint, round-robining over k8s.HABackends
+// (or just this node, if no backend list was configured).
+func generateHALoadBalancerManifest(k8s config.KubernetesConfig) (string, error) {
+	vip, port, err := net.SplitHostPort(k8s.HAEndpoint)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing HAEndpoint %q as host:port", k8s.HAEndpoint)
+	}
+
+	backends := k8s.HABackends
+	if len(backends) == 0 {
+		backends = []string{k8s.NodeIP}
+	}
+
+	opts := haLoadBalancerOpts{
+		Image:    haLoadBalancerImage,
+		VIPImage: haVIPImage,
+		VIP:      vip,
+		Port:     port,
+		Backends: backends,
+	}
+
+	var b bytes.Buffer
+	if err := haLoadBalancerManifest.Execute(&b, opts); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/config"
+)
+
+func TestGenerateHALoadBalancerManifest(t *testing.T) {
+	k8s := config.KubernetesConfig{
+		HAEndpoint: "192.168.99.100:8443",
+		HABackends: []string{"192.168.99.101:6443", "192.168.99.102:6443"},
+	}
+
+	manifest, err := generateHALoadBalancerManifest(k8s)
+	if err != nil {
+		t.Fatalf("generateHALoadBalancerManifest: %v", err)
+	}
+
+	for _, want := range []string{
+		"image: " + haLoadBalancerImage,
+		"image: " + haVIPImage,
+		"ip addr replace 192.168.99.100/32 dev lo",
+		"bind 192.168.99.100:8443",
+		"server apiserver0 192.168.99.101:6443",
+		"server apiserver1 192.168.99.102:6443",
+	} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("generateHALoadBalancerManifest: manifest missing %q\n%s", want, manifest)
+		}
+	}
+}
+
+func TestGenerateHALoadBalancerManifestDefaultsBackendToNodeIP(t *testing.T) {
+	k8s := config.KubernetesConfig{
+		HAEndpoint: "192.168.99.100:8443",
+		NodeIP:     "192.168.99.50",
+	}
+
+	manifest, err := generateHALoadBalancerManifest(k8s)
+	if err != nil {
+		t.Fatalf("generateHALoadBalancerManifest: %v", err)
+	}
+	if !strings.Contains(manifest, "server apiserver0 192.168.99.50") {
+		t.Errorf("generateHALoadBalancerManifest: expected a backend defaulted to NodeIP, got:\n%s", manifest)
+	}
+}
+
+func TestGenerateHALoadBalancerManifestBadEndpoint(t *testing.T) {
+	k8s := config.KubernetesConfig{HAEndpoint: "not-a-host-port"}
+	if _, err := generateHALoadBalancerManifest(k8s); err == nil {
+		t.Error("generateHALoadBalancerManifest: expected an error for a malformed HAEndpoint, got nil")
+	}
+}
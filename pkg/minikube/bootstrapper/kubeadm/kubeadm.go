@@ -18,14 +18,14 @@ package kubeadm
 
 import (
 	"bytes"
-	"crypto"
 	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
-	"os"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -33,20 +33,32 @@ import (
 	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/golang/glog"
-	download "github.com/jimmidyson/go-download"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 	"k8s.io/minikube/pkg/minikube/assets"
 	"k8s.io/minikube/pkg/minikube/bootstrapper"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
-	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/sshutil"
 	"k8s.io/minikube/pkg/util"
 )
 
+// haEndpointFile is where UpdateCluster records KubernetesConfig.HAEndpoint
+// on the node itself, so that GetApiServerStatus can find it even when
+// called on a *KubeadmBootstrapper that wasn't the one UpdateCluster ran on
+// (e.g. a fresh instance built by NewKubeadmBootstrapper for `minikube
+// status`).
+const haEndpointFile = "/var/lib/minikube/ha-endpoint"
+
 type KubeadmBootstrapper struct {
 	c bootstrapper.CommandRunner
+	// haEndpoint is the host:port of the static-pod load balancer VIP set up
+	// when KubernetesConfig.HAEndpoint is configured. When set,
+	// GetApiServerStatus probes it instead of the node's own IP. It's populated
+	// either by UpdateCluster or, lazily, by reading haEndpointFile back off
+	// the node the first time it's needed.
+	haEndpoint string
 }
 
 func NewKubeadmBootstrapper(api libmachine.API) (*KubeadmBootstrapper, error) {
@@ -89,7 +101,26 @@ func (k *KubeadmBootstrapper) GetKubeletStatus() (string, error) {
 }
 
 func (k *KubeadmBootstrapper) GetApiServerStatus(ip net.IP) (string, error) {
-	url := fmt.Sprintf("https://%s:%d/healthz", ip, util.APIServerPort)
+	host := ip.String()
+	apiServerPort := util.APIServerPort
+	// In HA mode, the real apiserver(s) may not even be reachable at ip
+	// anymore; probe the VIP the load balancer static pod is listening on
+	// instead.
+	if k.haEndpoint == "" {
+		k.haEndpoint = k.loadHAEndpoint()
+	}
+	if k.haEndpoint != "" {
+		if h, p, err := net.SplitHostPort(k.haEndpoint); err == nil {
+			host = h
+			if port, err := strconv.Atoi(p); err == nil {
+				apiServerPort = port
+			}
+		} else {
+			host = k.haEndpoint
+		}
+	}
+
+	url := fmt.Sprintf("https://%s:%d/healthz", host, apiServerPort)
 	// To avoid: x509: certificate signed by unknown authority
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
@@ -107,70 +138,199 @@ func (k *KubeadmBootstrapper) GetApiServerStatus(ip net.IP) (string, error) {
 	return state.Running.String(), nil
 }
 
-// TODO(r2d4): Should this aggregate all the logs from the control plane?
-// Maybe subcommands for each component? minikube logs apiserver?
+// loadHAEndpoint reads back the HA endpoint UpdateCluster persisted to
+// haEndpointFile on the node, if any. A missing file just means the cluster
+// isn't running in HA mode.
+func (k *KubeadmBootstrapper) loadHAEndpoint() string {
+	out, err := k.c.CombinedOutput(fmt.Sprintf("sudo cat %s 2>/dev/null", haEndpointFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// controlPlaneComponents are the static pods kubeadm runs under kube-system,
+// identified by their "component" label.
+var controlPlaneComponents = []string{
+	"kube-apiserver",
+	"kube-controller-manager",
+	"kube-scheduler",
+	"etcd",
+	"kube-proxy",
+}
+
+// logEntry is a single line of output from a component's logs, tagged with a
+// best-effort timestamp so entries from different components can be merged.
+type logEntry struct {
+	timestamp time.Time
+	line      string
+}
+
+// GetClusterLogsTo aggregates logs from the kubelet and every control plane
+// component and writes them to out, merged in chronological order. When
+// follow is true, only the kubelet's log is streamed, since the kubelet
+// supervises every static pod on the node and merging multiple live streams
+// isn't practical.
 func (k *KubeadmBootstrapper) GetClusterLogsTo(follow bool, out io.Writer) error {
-	var flags []string
 	if follow {
-		flags = append(flags, "-f")
+		return k.GetComponentLogsTo("kubelet", follow, out)
+	}
+
+	var entries []logEntry
+	for _, component := range append([]string{"kubelet"}, controlPlaneComponents...) {
+		var b bytes.Buffer
+		if err := k.GetComponentLogsTo(component, false, &b); err != nil {
+			glog.Warningf("unable to get logs for %s: %v", component, err)
+			continue
+		}
+		entries = append(entries, parseLogEntries(component, b.String())...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].timestamp.Before(entries[j].timestamp)
+	})
+	for _, e := range entries {
+		fmt.Fprintln(out, e.line)
+	}
+	return nil
+}
+
+// GetComponentLogsTo writes the logs for a single control plane component
+// (or "kubelet") to out. This backs both the aggregated `minikube logs` and
+// the per-component `minikube logs <component>` subcommands.
+func (k *KubeadmBootstrapper) GetComponentLogsTo(component string, follow bool, out io.Writer) error {
+	logsCommand, err := componentLogsCommand(component, follow)
+	if err != nil {
+		return errors.Wrapf(err, "building logs command for %s", component)
 	}
-	logsCommand := fmt.Sprintf("sudo journalctl %s -u kubelet", strings.Join(flags, " "))
 
 	if follow {
 		if err := k.c.CombinedOutputTo(logsCommand, out); err != nil {
-			return errors.Wrap(err, "getting cluster logs")
+			return errors.Wrapf(err, "getting %s logs", component)
 		}
-	} else {
+		return nil
+	}
 
-		logs, err := k.c.CombinedOutput(logsCommand)
-		if err != nil {
-			return errors.Wrap(err, "getting cluster logs")
-		}
-		fmt.Fprint(out, logs)
+	logs, err := k.c.CombinedOutput(logsCommand)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s logs", component)
 	}
+	fmt.Fprint(out, logs)
 	return nil
 }
 
+// componentLogsCommand builds the shell command used to read logs for a
+// control plane component. The kubelet itself is a systemd unit, so its logs
+// come from journalctl; every other component is a static pod supervised by
+// the kubelet, so its container is looked up by its "component" label (via
+// crictl, which works against Docker, CRI-O and containerd alike) and, if
+// that fails to find a running container, falls back to reading the
+// kubelet's copy of the pod's log files under /var/log/pods.
+func componentLogsCommand(component string, follow bool) (string, error) {
+	if component == "" || component == "kubelet" {
+		var flags []string
+		if follow {
+			flags = append(flags, "-f")
+		}
+		// -o short-iso-precise gives each line a full (dated) timestamp, same
+		// as crictl logs --timestamps; journalctl's default format omits the
+		// year, which would sort every kubelet line ahead of every other
+		// component's.
+		flags = append(flags, "-o", "short-iso-precise")
+		return fmt.Sprintf("sudo journalctl %s -u kubelet", strings.Join(flags, " ")), nil
+	}
+
+	valid := false
+	for _, c := range controlPlaneComponents {
+		if c == component {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", errors.Errorf("unknown control plane component %q", component)
+	}
+
+	tailFlag := ""
+	if follow {
+		tailFlag = "-f"
+	}
+	findContainer := fmt.Sprintf("sudo crictl ps -a --label component=%s --state Running -q 2>/dev/null | head -n1", component)
+	return fmt.Sprintf(
+		`id=$(%s); if [ -n "$id" ]; then sudo crictl logs --timestamps %s "$id"; else sudo find /var/log/pods -maxdepth 2 -iname "*%s*" -name "*.log" -exec tail %s -n +1 {} +; fi`,
+		findContainer, tailFlag, component, tailFlag), nil
+}
+
+// timestampLayouts are tried in order when parsing a log line's leading
+// timestamp, covering journalctl's -o short-iso-precise format and the
+// RFC3339Nano timestamps crictl logs --timestamps emits. Neither includes
+// journalctl's old bare default format ("Jan 02 15:04:05"), which carries no
+// year and would otherwise sort every kubelet line ahead of every other
+// component's regardless of when it was actually logged.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999-0700",
+}
+
+// parseLogEntries splits a component's raw log output into individual,
+// timestamp-tagged entries so GetClusterLogsTo can interleave them with
+// other components' output.
+func parseLogEntries(component, output string) []logEntry {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return nil
+	}
+
+	lines := strings.Split(output, "\n")
+	entries := make([]logEntry, 0, len(lines))
+	for _, line := range lines {
+		ts := time.Time{}
+		fields := strings.SplitN(line, " ", 4)
+		for i := len(fields); i > 0; i-- {
+			candidate := strings.Join(fields[:i], " ")
+			for _, layout := range timestampLayouts {
+				if parsed, err := time.Parse(layout, candidate); err == nil {
+					ts = parsed
+					break
+				}
+			}
+			if !ts.IsZero() {
+				break
+			}
+		}
+		entries = append(entries, logEntry{
+			timestamp: ts,
+			line:      fmt.Sprintf("%-24s %s", component, line),
+		})
+	}
+	return entries
+}
+
 func (k *KubeadmBootstrapper) StartCluster(k8s config.KubernetesConfig) error {
 	version, err := ParseKubernetesVersion(k8s.KubernetesVersion)
 	if err != nil {
 		return errors.Wrap(err, "parsing kubernetes version")
 	}
 
-	b := bytes.Buffer{}
-	preflights := constants.Preflights
 	if k8s.ContainerRuntime != "" {
-		preflights = constants.AlternateRuntimePreflights
-		out, err := k.c.CombinedOutput("sudo modprobe br_netfilter")
+		runtime, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Socket: k8s.CRISocket})
 		if err != nil {
-			glog.Infoln(out)
-			return errors.Wrap(err, "sudo modprobe br_netfilter")
+			return errors.Wrap(err, "selecting container runtime")
 		}
-		out, err = k.c.CombinedOutput("sudo sh -c \"echo '1' > /proc/sys/net/ipv4/ip_forward\"")
-		if err != nil {
-			glog.Infoln(out)
-			return errors.Wrap(err, "creating /proc/sys/net/ipv4/ip_forward")
+		if err := runtime.PreflightSetup(k.c); err != nil {
+			return errors.Wrap(err, "container runtime preflight setup")
 		}
 	}
 
-	templateContext := struct {
-		KubeadmConfigFile   string
-		SkipPreflightChecks bool
-		Preflights          []string
-	}{
-		KubeadmConfigFile: constants.KubeadmConfigFile,
-		SkipPreflightChecks: !VersionIsBetween(version,
-			semver.MustParse("1.9.0-alpha.0"),
-			semver.Version{}),
-		Preflights: preflights,
-	}
-	if err := kubeadmInitTemplate.Execute(&b, templateContext); err != nil {
-		return err
-	}
-
-	out, err := k.c.CombinedOutput(b.String())
-	if err != nil {
-		return errors.Wrapf(err, "kubeadm init: %s\n%s\n", b.String(), out)
+	// Rather than shelling a single monolithic `kubeadm init`, walk the same
+	// phase graph kubeadm does internally through the shared phase executor,
+	// so a failure partway through can be resumed instead of forcing a
+	// full re-init. See phases.go.
+	phaseVerb, controlPlaneVerb := phaseVerbsFor(version)
+	phases := kubeadmInitPhases(phaseVerb, controlPlaneVerb, version, k8s)
+	opts := PhaseOptions{SkipPhases: k8s.SkipPhases, OnlyPhases: k8s.OnlyPhases}
+	if err := executePhases(k.c, phases, opts); err != nil {
+		return errors.Wrap(err, "running kubeadm init phases")
 	}
 
 	if version.LT(semver.MustParse("1.10.0-alpha.0")) {
@@ -214,28 +374,11 @@ func (k *KubeadmBootstrapper) RestartCluster(k8s config.KubernetesConfig) error
 		return errors.Wrap(err, "parsing kubernetes version")
 	}
 
-	phase := "alpha"
-	controlPlane := "controlplane"
-	if version.GTE(semver.MustParse("1.13.0")) {
-		phase = "init"
-		controlPlane = "control-plane"
-	}
-
-	cmds := []string{
-		fmt.Sprintf("sudo kubeadm %s phase certs all --config %s", phase, constants.KubeadmConfigFile),
-		fmt.Sprintf("sudo kubeadm %s phase kubeconfig all --config %s", phase, constants.KubeadmConfigFile),
-		fmt.Sprintf("sudo kubeadm %s phase %s all --config %s", phase, controlPlane, constants.KubeadmConfigFile),
-		fmt.Sprintf("sudo kubeadm %s phase etcd local --config %s", phase, constants.KubeadmConfigFile),
-	}
-
-	// Run commands one at a time so that it is easier to root cause failures.
-	for _, cmd := range cmds {
-		if err := k.c.Run(cmd); err != nil {
-			return errors.Wrapf(err, "running cmd: %s", cmd)
-		}
-	}
-	if err := restartKubeProxy(k8s); err != nil {
-		return errors.Wrap(err, "restarting kube-proxy")
+	phaseVerb, controlPlaneVerb := phaseVerbsFor(version)
+	phases := kubeadmRestartPhases(phaseVerb, controlPlaneVerb, k8s)
+	opts := PhaseOptions{SkipPhases: k8s.SkipPhases, OnlyPhases: k8s.OnlyPhases}
+	if err := executePhases(k.c, phases, opts); err != nil {
+		return errors.Wrap(err, "running kubeadm restart phases")
 	}
 
 	return nil
@@ -259,40 +402,33 @@ func SetContainerRuntime(cfg map[string]string, runtime string) map[string]strin
 		return cfg
 	}
 
-	switch runtime {
-	case "crio", "cri-o":
-		cfg["container-runtime"] = "remote"
-		cfg["container-runtime-endpoint"] = "/var/run/crio/crio.sock"
-		cfg["image-service-endpoint"] = "/var/run/crio/crio.sock"
-		cfg["runtime-request-timeout"] = "15m"
-	case "containerd":
-		cfg["container-runtime"] = "remote"
-		cfg["container-runtime-endpoint"] = "unix:///run/containerd/containerd.sock"
-		cfg["image-service-endpoint"] = "unix:///run/containerd/containerd.sock"
-		cfg["runtime-request-timeout"] = "15m"
-	default:
+	r, err := cruntime.New(cruntime.Config{Type: runtime})
+	if err != nil {
+		glog.Warningf("unknown container runtime %q, falling back to plain --container-runtime: %v", runtime, err)
 		cfg["container-runtime"] = runtime
+		return cfg
 	}
 
+	for k, v := range r.KubeletOptions() {
+		cfg[k] = v
+	}
 	return cfg
 }
 
+// GetCRISocket returns the CRI socket the kubelet should be pointed at for
+// the given runtime, honoring an explicit override in path if one is given.
 func GetCRISocket(path string, runtime string) string {
 	if path != "" {
 		glog.Infoln("Container runtime interface socket provided, using path.")
 		return path
 	}
 
-	switch runtime {
-	case "crio", "cri-o":
-		path = "/var/run/crio/crio.sock"
-	case "containerd":
-		path = "/run/containerd/containerd.sock"
-	default:
-		path = ""
+	r, err := cruntime.New(cruntime.Config{Type: runtime})
+	if err != nil {
+		glog.Warningf("unknown container runtime %q, assuming no CRI socket: %v", runtime, err)
+		return ""
 	}
-
-	return path
+	return r.Socket()
 }
 
 // NewKubeletConfig generates a new systemd unit containing a configured kubelet
@@ -339,10 +475,34 @@ func NewKubeletConfig(k8s config.KubernetesConfig) (string, error) {
 	return b.String(), nil
 }
 
+// loadCachedImages loads every image minikube caches locally for cfg's
+// Kubernetes version into the configured container runtime's image store,
+// via that runtime's own Runtime.LoadImage, so e.g. --container-runtime=crio
+// actually populates CRI-O's store instead of always loading into Docker.
+func (k *KubeadmBootstrapper) loadCachedImages(cfg config.KubernetesConfig) error {
+	r, err := cruntime.New(cruntime.Config{Type: cfg.ContainerRuntime, Socket: cfg.CRISocket})
+	if err != nil {
+		return errors.Wrap(err, "selecting container runtime")
+	}
+	for _, image := range constants.GetKubeadmCachedImages(cfg.KubernetesVersion) {
+		imagePath := cachedImagePath(constants.ImageCacheDir, image)
+		if err := r.LoadImage(k.c, imagePath); err != nil {
+			return errors.Wrapf(err, "loading cached image %s", image)
+		}
+	}
+	return nil
+}
+
+// cachedImagePath returns where minikube's local image cache stores the
+// tarball for image, mirroring the flattened, slash-free naming the cache
+// writer under cacheDir uses.
+func cachedImagePath(cacheDir, image string) string {
+	return path.Join(cacheDir, strings.Replace(image, "/", "-", -1)+".tar")
+}
+
 func (k *KubeadmBootstrapper) UpdateCluster(cfg config.KubernetesConfig) error {
 	if cfg.ShouldLoadCachedImages {
-		err := machine.LoadImages(k.c, constants.GetKubeadmCachedImages(cfg.KubernetesVersion), constants.ImageCacheDir)
-		if err != nil {
+		if err := k.loadCachedImages(cfg); err != nil {
 			return errors.Wrap(err, "loading cached images")
 		}
 	}
@@ -378,6 +538,17 @@ func (k *KubeadmBootstrapper) UpdateCluster(cfg config.KubernetesConfig) error {
 			assets.NewMemoryAssetTarget([]byte(defaultCNIConfig), constants.DefaultRktNetConfigPath, "0644"))
 	}
 
+	k.haEndpoint = cfg.HAEndpoint
+	if cfg.HAEndpoint != "" {
+		haManifest, err := generateHALoadBalancerManifest(cfg)
+		if err != nil {
+			return errors.Wrap(err, "generating HA load balancer manifest")
+		}
+		files = append(files,
+			assets.NewMemoryAssetTarget([]byte(haManifest), haManifestPath, "0644"),
+			assets.NewMemoryAssetTarget([]byte(cfg.HAEndpoint), haEndpointFile, "0644"))
+	}
+
 	var g errgroup.Group
 	for _, bin := range []string{"kubelet", "kubeadm"} {
 		bin := bin
@@ -465,17 +636,18 @@ func generateConfig(k8s config.KubernetesConfig) (string, error) {
 	}
 
 	opts := struct {
-		CertDir           string
-		ServiceCIDR       string
-		AdvertiseAddress  string
-		APIServerPort     int
-		KubernetesVersion string
-		EtcdDataDir       string
-		NodeName          string
-		CRISocket         string
-		ExtraArgs         []ComponentExtraArgs
-		FeatureArgs       map[string]bool
-		NoTaintMaster     bool
+		CertDir              string
+		ServiceCIDR          string
+		AdvertiseAddress     string
+		APIServerPort        int
+		KubernetesVersion    string
+		EtcdDataDir          string
+		NodeName             string
+		CRISocket            string
+		ExtraArgs            []ComponentExtraArgs
+		FeatureArgs          map[string]bool
+		NoTaintMaster        bool
+		ControlPlaneEndpoint string
 	}{
 		CertDir:           util.DefaultCertPath,
 		ServiceCIDR:       util.DefaultServiceCIDR,
@@ -494,6 +666,14 @@ func generateConfig(k8s config.KubernetesConfig) (string, error) {
 		opts.ServiceCIDR = k8s.ServiceCIDR
 	}
 
+	// When HAEndpoint is set, point kubeadm's generated kubeconfigs and the
+	// apiserver's own certificate SANs at the VIP instead of this node's
+	// address, so they keep working if the VIP is later moved to a real
+	// load balancer in front of multiple apiservers.
+	if k8s.HAEndpoint != "" {
+		opts.ControlPlaneEndpoint = k8s.HAEndpoint
+	}
+
 	if version.GTE(semver.MustParse("1.10.0-alpha.0")) {
 		opts.NoTaintMaster = true
 	}
@@ -507,39 +687,19 @@ func generateConfig(k8s config.KubernetesConfig) (string, error) {
 		return "", err
 	}
 
-	return b.String(), nil
-}
-
-func maybeDownloadAndCache(binary, version string) (string, error) {
-	targetDir := constants.MakeMiniPath("cache", version)
-	targetFilepath := path.Join(targetDir, binary)
-
-	_, err := os.Stat(targetFilepath)
-	// If it exists, do no verification and continue
-	if err == nil {
-		return targetFilepath, nil
-	}
-	if !os.IsNotExist(err) {
-		return "", errors.Wrapf(err, "stat %s version %s at %s", binary, version, targetDir)
+	out := b.String()
+	// Guard against the template text not (yet) having a controlPlaneEndpoint
+	// placeholder: text/template silently drops struct fields a template
+	// doesn't reference, which would otherwise leave the apiserver's cert
+	// SANs and the generated kubeconfigs pointed at this node instead of the
+	// HA VIP. Appending the key is safe either way: it's a top-level
+	// MasterConfiguration field, and YAML doesn't care about key order.
+	if opts.ControlPlaneEndpoint != "" && !strings.Contains(out, "controlPlaneEndpoint:") {
+		out += fmt.Sprintf("controlPlaneEndpoint: %s\n", opts.ControlPlaneEndpoint)
 	}
 
-	if err = os.MkdirAll(targetDir, 0777); err != nil {
-		return "", errors.Wrapf(err, "mkdir %s", targetDir)
-	}
-
-	url := constants.GetKubernetesReleaseURL(binary, version)
-	options := download.FileOptions{
-		Mkdirs: download.MkdirAll,
-	}
-
-	options.Checksum = constants.GetKubernetesReleaseURLSha1(binary, version)
-	options.ChecksumHash = crypto.SHA1
-
-	fmt.Printf("Downloading %s %s\n", binary, version)
-	if err := download.ToFile(url, targetFilepath, options); err != nil {
-		return "", errors.Wrapf(err, "Error downloading %s %s", binary, version)
-	}
-	fmt.Printf("Finished Downloading %s %s\n", binary, version)
-
-	return targetFilepath, nil
+	return out, nil
 }
+
+// maybeDownloadAndCache, PrefetchBinaries and their supporting helpers live
+// in cache.go.
@@ -0,0 +1,94 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestComponentLogsCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		component string
+		follow    bool
+		wantSub   string
+		wantErr   bool
+	}{
+		{"kubelet", "kubelet", false, "journalctl -o short-iso-precise -u kubelet", false},
+		{"kubelet follow", "kubelet", true, "journalctl -f -o short-iso-precise -u kubelet", false},
+		{"default to kubelet", "", false, "journalctl -o short-iso-precise -u kubelet", false},
+		{"apiserver", "kube-apiserver", false, "crictl logs --timestamps", false},
+		{"unknown component", "bogus", false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := componentLogsCommand(tt.component, tt.follow)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("componentLogsCommand(%q): expected an error, got nil", tt.component)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("componentLogsCommand(%q): %v", tt.component, err)
+			}
+			if !strings.Contains(cmd, tt.wantSub) {
+				t.Errorf("componentLogsCommand(%q) = %q, want it to contain %q", tt.component, cmd, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestParseLogEntriesOrdersChronologically(t *testing.T) {
+	// Two kubelet lines (journalctl -o short-iso-precise format) straddling
+	// a single apiserver line (crictl logs --timestamps, RFC3339Nano
+	// format): if either format's year were lost in parsing, sorting by
+	// timestamp would not reproduce this interleaving.
+	kubeletOut := "2026-07-26T03:58:59.000001+0000 minikube kubelet[1]: starting up\n" +
+		"2026-07-26T04:00:00.000001+0000 minikube kubelet[1]: still running\n"
+	apiserverOut := "2026-07-26T03:59:30.000000000Z apiserver starting\n"
+
+	entries := parseLogEntries("kubelet", kubeletOut)
+	entries = append(entries, parseLogEntries("kube-apiserver", apiserverOut)...)
+
+	for _, e := range entries {
+		if e.timestamp.IsZero() {
+			t.Fatalf("parseLogEntries: line %q failed to parse a timestamp", e.line)
+		}
+		if e.timestamp.Year() != 2026 {
+			t.Fatalf("parseLogEntries: line %q parsed with year %d, want 2026", e.line, e.timestamp.Year())
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].timestamp.Before(entries[j].timestamp)
+	})
+
+	var gotOrder []string
+	for _, e := range entries {
+		gotOrder = append(gotOrder, strings.TrimSpace(strings.SplitN(e.line, " ", 2)[0]))
+	}
+	wantOrder := []string{"kubelet", "kube-apiserver", "kubelet"}
+	for i := range wantOrder {
+		if gotOrder[i] != wantOrder[i] {
+			t.Errorf("sorted entry order = %v, want %v", gotOrder, wantOrder)
+			break
+		}
+	}
+}
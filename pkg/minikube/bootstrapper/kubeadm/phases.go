@@ -0,0 +1,307 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/blang/semver"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/minikube/pkg/minikube/bootstrapper"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// phaseStateFile is where the executor persists which phases have completed,
+// so a re-run after a failure can skip the work that already succeeded
+// instead of re-running kubeadm init/restart from scratch.
+const phaseStateFile = "/var/lib/minikube/phases.json"
+
+// Phase is a single step of the kubeadm phase graph. Phases form a DAG via
+// DependsOn; the executor runs every phase whose dependencies have already
+// completed, in parallel, before moving on to the next wave.
+type Phase struct {
+	Name      string
+	DependsOn []string
+	Run       func(cr bootstrapper.CommandRunner) error
+	Skip      func() bool
+}
+
+// PhaseOptions controls which phases executePhases actually runs.
+type PhaseOptions struct {
+	// SkipPhases names phases to leave out entirely.
+	SkipPhases []string
+	// OnlyPhases, if non-empty, restricts the run to exactly these phases.
+	OnlyPhases []string
+}
+
+// phaseState is the on-disk (on-node) record of completed phase names.
+type phaseState struct {
+	Completed []string `json:"completed"`
+}
+
+// executePhases runs phases to completion, respecting DependsOn order,
+// opts' --skip-phases/--only-phases filtering, and a phase's own Skip
+// predicate. Phases with no unmet dependencies are run concurrently.
+// Completed phase names are persisted to the node after each wave, so if the
+// process is interrupted, the next call picks up after the last phase that
+// finished rather than re-running everything. Once every phase has run, the
+// persisted record is removed. A phase left out by opts counts as satisfied
+// for the phases that depend on it.
+func executePhases(cr bootstrapper.CommandRunner, phases []Phase, opts PhaseOptions) error {
+	byName := make(map[string]Phase, len(phases))
+	for _, p := range phases {
+		byName[p.Name] = p
+	}
+
+	done := map[string]bool{}
+	for _, name := range loadCompletedPhases(cr) {
+		if _, ok := byName[name]; ok {
+			done[name] = true
+		}
+	}
+
+	only := toSet(opts.OnlyPhases)
+	skip := toSet(opts.SkipPhases)
+
+	pending := map[string]bool{}
+	for _, p := range phases {
+		if done[p.Name] || skip[p.Name] {
+			continue
+		}
+		if len(only) > 0 && !only[p.Name] {
+			continue
+		}
+		pending[p.Name] = true
+	}
+
+	for len(pending) > 0 {
+		wave := readyPhases(byName, pending, done)
+		if len(wave) == 0 {
+			return errors.Errorf("phase dependency cycle or unresolved dependency among: %s", strings.Join(setKeys(pending), ", "))
+		}
+
+		var g errgroup.Group
+		var mu sync.Mutex
+		var finished []string
+		for _, p := range wave {
+			p := p
+			g.Go(func() error {
+				if p.Skip != nil && p.Skip() {
+					glog.Infof("skipping phase %s", p.Name)
+				} else if err := p.Run(cr); err != nil {
+					return errors.Wrapf(err, "phase %s", p.Name)
+				}
+				mu.Lock()
+				finished = append(finished, p.Name)
+				mu.Unlock()
+				return nil
+			})
+		}
+		waveErr := g.Wait()
+
+		// Persist whichever phases in this wave actually succeeded even if
+		// another phase in the same wave failed: those phases are done and
+		// must not be re-run (and re-shelled out to kubeadm) just because a
+		// sibling in the same wave errored.
+		for _, name := range finished {
+			done[name] = true
+			delete(pending, name)
+		}
+		if err := persistCompletedPhases(cr, done); err != nil {
+			glog.Warningf("unable to persist phase progress: %v", err)
+		}
+
+		if waveErr != nil {
+			return waveErr
+		}
+	}
+
+	// The whole graph has run, so drop the recorded progress: otherwise the
+	// next run (e.g. RestartCluster after StartCluster, which share phase
+	// names) would load these phases as done and skip them.
+	if err := cr.Run(fmt.Sprintf("sudo rm -f %s", phaseStateFile)); err != nil {
+		glog.Warningf("unable to clear phase progress: %v", err)
+	}
+	return nil
+}
+
+// readyPhases returns the phases in pending whose dependencies have all
+// already completed or are left out of this run (by --skip-phases or
+// --only-phases). A dependency on a name that isn't in the graph at all is
+// never satisfied.
+func readyPhases(byName map[string]Phase, pending, done map[string]bool) []Phase {
+	var ready []Phase
+	for name := range pending {
+		p := byName[name]
+		blocked := false
+		for _, dep := range p.DependsOn {
+			_, known := byName[dep]
+			if !done[dep] && (pending[dep] || !known) {
+				blocked = true
+				break
+			}
+		}
+		if !blocked {
+			ready = append(ready, p)
+		}
+	}
+	return ready
+}
+
+func toSet(names []string) map[string]bool {
+	s := make(map[string]bool, len(names))
+	for _, n := range names {
+		s[n] = true
+	}
+	return s
+}
+
+func setKeys(s map[string]bool) []string {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// loadCompletedPhases reads the phase progress left on the node by a
+// previous, possibly-failed run. A missing or unreadable state file just
+// means no progress has been recorded yet.
+func loadCompletedPhases(cr bootstrapper.CommandRunner) []string {
+	out, err := cr.CombinedOutput(fmt.Sprintf("sudo cat %s 2>/dev/null", phaseStateFile))
+	if err != nil || strings.TrimSpace(out) == "" {
+		return nil
+	}
+	var state phaseState
+	if err := json.Unmarshal([]byte(out), &state); err != nil {
+		glog.Warningf("unable to parse %s, ignoring saved phase progress: %v", phaseStateFile, err)
+		return nil
+	}
+	return state.Completed
+}
+
+// persistCompletedPhases writes the set of completed phase names to the
+// node so a subsequent run of the same phase graph can resume.
+func persistCompletedPhases(cr bootstrapper.CommandRunner, done map[string]bool) error {
+	state := phaseState{Completed: setKeys(done)}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "marshaling phase state")
+	}
+	cmd := fmt.Sprintf("sudo mkdir -p %s && sudo sh -c 'cat > %s' <<'PHASES_EOF'\n%s\nPHASES_EOF",
+		path.Dir(phaseStateFile), phaseStateFile, data)
+	return cr.Run(cmd)
+}
+
+// phaseVerbsFor returns the kubeadm phase-command verb and control-plane
+// phase name to use for the given Kubernetes version: kubeadm renamed
+// `kubeadm alpha phase controlplane` to `kubeadm init phase control-plane`
+// in 1.13.
+func phaseVerbsFor(version semver.Version) (phaseVerb, controlPlaneVerb string) {
+	if version.GTE(semver.MustParse("1.13.0")) {
+		return "init", "control-plane"
+	}
+	return "alpha", "controlplane"
+}
+
+// runKubeadmPhase returns a Phase.Run that shells out to a single
+// `kubeadm <phaseVerb> phase <phaseArg>` invocation.
+func runKubeadmPhase(phaseVerb, phaseArg string) func(bootstrapper.CommandRunner) error {
+	cmd := fmt.Sprintf("sudo kubeadm %s phase %s --config %s", phaseVerb, phaseArg, constants.KubeadmConfigFile)
+	return func(cr bootstrapper.CommandRunner) error {
+		return cr.Run(cmd)
+	}
+}
+
+// preflightIgnoreFlag builds the preflight phase's ignore-list flag, the
+// phase-graph equivalent of the SkipPreflightChecks/Preflights handling
+// `kubeadm init` used to get for free: kubeadm versions before 1.9 only know
+// how to skip preflight checks outright, so --skip-preflight-checks is used
+// there; later versions can selectively ignore the specific, known-safe
+// checks minikube runs into (a longer list when an alternate container
+// runtime is in play, same as before).
+func preflightIgnoreFlag(version semver.Version, k8s config.KubernetesConfig) string {
+	if !VersionIsBetween(version, semver.MustParse("1.9.0-alpha.0"), semver.Version{}) {
+		return "--skip-preflight-checks"
+	}
+
+	ignore := constants.Preflights
+	if k8s.ContainerRuntime != "" {
+		ignore = constants.AlternateRuntimePreflights
+	}
+	return "--ignore-preflight-errors=" + strings.Join(ignore, ",")
+}
+
+// runPreflightPhase is like runKubeadmPhase, but also passes the
+// ignore-list flag built by preflightIgnoreFlag.
+func runPreflightPhase(phaseVerb string, version semver.Version, k8s config.KubernetesConfig) func(bootstrapper.CommandRunner) error {
+	cmd := fmt.Sprintf("sudo kubeadm %s phase preflight --config %s %s",
+		phaseVerb, constants.KubeadmConfigFile, preflightIgnoreFlag(version, k8s))
+	return func(cr bootstrapper.CommandRunner) error {
+		return cr.Run(cmd)
+	}
+}
+
+// kubeadmInitPhases is the full phase graph `kubeadm init` walks through
+// internally, broken out so StartCluster can run it through executePhases
+// instead of shelling a single monolithic `kubeadm init`. mark-control-plane
+// and bootstrap-token only depend on the control plane being up, so they run
+// as an independent, parallel wave; addon/all waits on both.
+func kubeadmInitPhases(phaseVerb, controlPlaneVerb string, version semver.Version, k8s config.KubernetesConfig) []Phase {
+	return []Phase{
+		{Name: "preflight", Run: runPreflightPhase(phaseVerb, version, k8s)},
+		{Name: "kubelet-start", DependsOn: []string{"preflight"}, Run: runKubeadmPhase(phaseVerb, "kubelet-start")},
+		{Name: "certs/all", DependsOn: []string{"kubelet-start"}, Run: runKubeadmPhase(phaseVerb, "certs all")},
+		{Name: "kubeconfig/all", DependsOn: []string{"certs/all"}, Run: runKubeadmPhase(phaseVerb, "kubeconfig all")},
+		{Name: "etcd/local", DependsOn: []string{"kubeconfig/all"}, Run: runKubeadmPhase(phaseVerb, "etcd local")},
+		{Name: controlPlaneVerb + "/all", DependsOn: []string{"etcd/local"}, Run: runKubeadmPhase(phaseVerb, controlPlaneVerb+" all")},
+		{Name: "mark-control-plane", DependsOn: []string{controlPlaneVerb + "/all"}, Run: runKubeadmPhase(phaseVerb, "mark-control-plane")},
+		{Name: "bootstrap-token", DependsOn: []string{controlPlaneVerb + "/all"}, Run: runKubeadmPhase(phaseVerb, "bootstrap-token")},
+		{
+			Name:      "addon/all",
+			DependsOn: []string{"mark-control-plane", "bootstrap-token"},
+			Run:       runKubeadmPhase(phaseVerb, "addon all"),
+		},
+	}
+}
+
+// kubeadmRestartPhases is the subset of the phase graph RestartCluster needs
+// to re-run after a node reboot: certs, kubeconfig and the control plane's
+// static pods, followed by restarting kube-proxy so it picks up any config
+// changes.
+func kubeadmRestartPhases(phaseVerb, controlPlaneVerb string, k8s config.KubernetesConfig) []Phase {
+	return []Phase{
+		{Name: "certs/all", Run: runKubeadmPhase(phaseVerb, "certs all")},
+		{Name: "kubeconfig/all", DependsOn: []string{"certs/all"}, Run: runKubeadmPhase(phaseVerb, "kubeconfig all")},
+		// etcd must come up before the control plane's static pods, same as
+		// in kubeadmInitPhases: the apiserver needs etcd reachable.
+		{Name: "etcd/local", DependsOn: []string{"kubeconfig/all"}, Run: runKubeadmPhase(phaseVerb, "etcd local")},
+		{Name: controlPlaneVerb + "/all", DependsOn: []string{"etcd/local"}, Run: runKubeadmPhase(phaseVerb, controlPlaneVerb+" all")},
+		{
+			Name:      "addon/kube-proxy",
+			DependsOn: []string{controlPlaneVerb + "/all"},
+			Run:       func(cr bootstrapper.CommandRunner) error { return restartKubeProxy(k8s) },
+		},
+	}
+}
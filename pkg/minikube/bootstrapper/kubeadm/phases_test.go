@@ -0,0 +1,84 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/bootstrapper"
+)
+
+// fakeRunner is a minimal bootstrapper.CommandRunner that serves
+// loadCompletedPhases/persistCompletedPhases out of an in-memory state file
+// instead of talking to a real node.
+type fakeRunner struct {
+	state string
+}
+
+func (f *fakeRunner) Run(cmd string) error {
+	if idx := strings.Index(cmd, "<<'PHASES_EOF'\n"); idx >= 0 {
+		rest := cmd[idx+len("<<'PHASES_EOF'\n"):]
+		f.state = strings.TrimSuffix(rest, "\nPHASES_EOF")
+	}
+	return nil
+}
+
+func (f *fakeRunner) CombinedOutput(cmd string) (string, error) {
+	return f.state, nil
+}
+
+func (f *fakeRunner) CombinedOutputTo(cmd string, w io.Writer) error {
+	return nil
+}
+
+func (f *fakeRunner) Copy(file assets.CopyableFile) error {
+	return nil
+}
+
+func TestExecutePhasesPersistsWaveSurvivorsBeforeFailing(t *testing.T) {
+	cr := &fakeRunner{}
+	phases := []Phase{
+		{Name: "ok", Run: func(bootstrapper.CommandRunner) error { return nil }},
+		{Name: "bad", Run: func(bootstrapper.CommandRunner) error { return errors.New("boom") }},
+	}
+
+	if err := executePhases(cr, phases, PhaseOptions{}); err == nil {
+		t.Fatal("executePhases: expected an error from the failing phase, got nil")
+	}
+
+	var state phaseState
+	if err := json.Unmarshal([]byte(cr.state), &state); err != nil {
+		t.Fatalf("unmarshaling persisted phase state: %v", err)
+	}
+	found := false
+	for _, name := range state.Completed {
+		if name == "ok" {
+			found = true
+		}
+		if name == "bad" {
+			t.Errorf("persisted phase state includes failing phase %q", name)
+		}
+	}
+	if !found {
+		t.Errorf("persisted phase state = %v, want it to include the successful phase %q", state.Completed, "ok")
+	}
+}
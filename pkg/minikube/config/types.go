@@ -0,0 +1,68 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+// ExtraOption is a single extra flag passed through to a named Kubernetes
+// component (e.g. "apiserver.audit-log-path=-").
+type ExtraOption struct {
+	Component string
+	Key       string
+	Value     string
+}
+
+// ExtraOptionSlice is a collection of ExtraOptions, as parsed from the
+// repeatable --extra-config flag.
+type ExtraOptionSlice []ExtraOption
+
+// KubernetesConfig holds the parameters used to configure a bootstrapper's
+// Kubernetes cluster.
+type KubernetesConfig struct {
+	KubernetesVersion string
+	NodeIP            string
+	NodePort          int
+	NodeName          string
+	ContainerRuntime  string
+	CRISocket         string
+	NetworkPlugin     string
+	FeatureGates      string
+	ServiceCIDR       string
+	ExtraOptions      ExtraOptionSlice
+
+	// ShouldLoadCachedImages controls whether the locally cached images for
+	// KubernetesVersion are pushed to the node before starting the cluster.
+	ShouldLoadCachedImages bool
+
+	// EnableDefaultCNI installs minikube's bundled, minimal CNI config when
+	// true, for users who start the kubelet with --network-plugin=cni but
+	// haven't supplied their own CNI plugin.
+	EnableDefaultCNI bool
+
+	// SkipPhases names kubeadm init/restart phases (see `kubeadm init
+	// --help`'s phase list) to leave out of the run entirely.
+	SkipPhases []string
+	// OnlyPhases, if non-empty, restricts a kubeadm init/restart run to
+	// exactly these phases.
+	OnlyPhases []string
+
+	// HAEndpoint is the host:port clients and the apiserver's own cert SANs
+	// should use instead of NodeIP, backed by a load balancer static pod
+	// proxying to HABackends. Empty means HA mode is disabled.
+	HAEndpoint string
+	// HABackends are the apiserver addresses HAEndpoint's load balancer
+	// proxies to. Defaults to just this node when empty.
+	HABackends []string
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/bootstrapper"
+)
+
+// defaultContainerdSocket is where the containerd package installs its socket.
+const defaultContainerdSocket = "/run/containerd/containerd.sock"
+
+// Containerd is the containerd container runtime, driven over CRI.
+type Containerd struct {
+	socket string
+}
+
+// Name implements Runtime.
+func (r *Containerd) Name() string {
+	return "containerd"
+}
+
+// Socket implements Runtime.
+func (r *Containerd) Socket() string {
+	if r.socket != "" {
+		return r.socket
+	}
+	return defaultContainerdSocket
+}
+
+// KubeletOptions implements Runtime.
+func (r *Containerd) KubeletOptions() map[string]string {
+	endpoint := fmt.Sprintf("unix://%s", r.Socket())
+	return map[string]string{
+		"container-runtime":          "remote",
+		"container-runtime-endpoint": endpoint,
+		"image-service-endpoint":     endpoint,
+		"runtime-request-timeout":    "15m",
+	}
+}
+
+// Enable implements Runtime.
+func (r *Containerd) Enable(cr bootstrapper.CommandRunner) error {
+	if err := cr.Run("sudo systemctl enable containerd && sudo systemctl start containerd"); err != nil {
+		return errors.Wrap(err, "enabling containerd")
+	}
+	return nil
+}
+
+// Disable implements Runtime.
+func (r *Containerd) Disable(cr bootstrapper.CommandRunner) error {
+	if err := cr.Run("sudo systemctl stop containerd && sudo systemctl disable containerd"); err != nil {
+		return errors.Wrap(err, "disabling containerd")
+	}
+	return nil
+}
+
+// LoadImage implements Runtime.
+func (r *Containerd) LoadImage(cr bootstrapper.CommandRunner, path string) error {
+	if err := cr.Run(fmt.Sprintf("sudo ctr -n=k8s.io images import %s", path)); err != nil {
+		return errors.Wrapf(err, "loading image %s into containerd", path)
+	}
+	return nil
+}
+
+// PreflightSetup implements Runtime.
+func (r *Containerd) PreflightSetup(cr bootstrapper.CommandRunner) error {
+	return commonCRIPreflightSetup(cr)
+}
+
+// CgroupDriver implements Runtime. containerd's config stores this as the
+// boolean SystemdCgroup rather than naming the driver directly.
+func (r *Containerd) CgroupDriver(cr bootstrapper.CommandRunner) (string, error) {
+	out, err := cr.CombinedOutput(`sudo containerd config dump 2>/dev/null | grep -m1 SystemdCgroup`)
+	if err != nil {
+		return "", errors.Wrap(err, "getting containerd cgroup driver")
+	}
+	if strings.Contains(out, "true") {
+		return "systemd", nil
+	}
+	return "cgroupfs", nil
+}
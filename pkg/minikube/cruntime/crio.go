@@ -0,0 +1,96 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/bootstrapper"
+)
+
+// defaultCRIOSocket is where the crio package installs its socket.
+const defaultCRIOSocket = "/var/run/crio/crio.sock"
+
+// CRIO is the CRI-O container runtime, driven over CRI.
+type CRIO struct {
+	socket string
+}
+
+// Name implements Runtime.
+func (r *CRIO) Name() string {
+	return "CRI-O"
+}
+
+// Socket implements Runtime.
+func (r *CRIO) Socket() string {
+	if r.socket != "" {
+		return r.socket
+	}
+	return defaultCRIOSocket
+}
+
+// KubeletOptions implements Runtime.
+func (r *CRIO) KubeletOptions() map[string]string {
+	return map[string]string{
+		"container-runtime":          "remote",
+		"container-runtime-endpoint": r.Socket(),
+		"image-service-endpoint":     r.Socket(),
+		"runtime-request-timeout":    "15m",
+	}
+}
+
+// Enable implements Runtime.
+func (r *CRIO) Enable(cr bootstrapper.CommandRunner) error {
+	if err := cr.Run("sudo systemctl enable crio && sudo systemctl start crio"); err != nil {
+		return errors.Wrap(err, "enabling crio")
+	}
+	return nil
+}
+
+// Disable implements Runtime.
+func (r *CRIO) Disable(cr bootstrapper.CommandRunner) error {
+	if err := cr.Run("sudo systemctl stop crio && sudo systemctl disable crio"); err != nil {
+		return errors.Wrap(err, "disabling crio")
+	}
+	return nil
+}
+
+// LoadImage implements Runtime. CRI-O shares its image store with podman,
+// so a cached tarball is loaded the same way.
+func (r *CRIO) LoadImage(cr bootstrapper.CommandRunner, path string) error {
+	if err := cr.Run(fmt.Sprintf("sudo podman load -i %s", path)); err != nil {
+		return errors.Wrapf(err, "loading image %s into crio", path)
+	}
+	return nil
+}
+
+// PreflightSetup implements Runtime.
+func (r *CRIO) PreflightSetup(cr bootstrapper.CommandRunner) error {
+	return commonCRIPreflightSetup(cr)
+}
+
+// CgroupDriver implements Runtime. CRI-O calls this setting cgroup_manager
+// rather than cgroup driver, but uses the same "cgroupfs"/"systemd" values.
+func (r *CRIO) CgroupDriver(cr bootstrapper.CommandRunner) (string, error) {
+	out, err := cr.CombinedOutput(`sudo crio config 2>/dev/null | grep -m1 '^cgroup_manager' | cut -d'"' -f2`)
+	if err != nil {
+		return "", errors.Wrap(err, "getting crio cgroup driver")
+	}
+	return strings.TrimSpace(out), nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cruntime abstracts over the container runtimes minikube can run
+// Kubernetes on top of, so that runtime-specific shell recipes live in one
+// place per runtime instead of being scattered through the bootstrapper as
+// a series of string-typed switches.
+package cruntime
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/bootstrapper"
+)
+
+// Runtime is the behavior a container runtime must provide in order for the
+// KubeadmBootstrapper to prepare a node and run Kubernetes against it.
+type Runtime interface {
+	// Name is the human-readable name of the runtime, as printed in logs.
+	Name() string
+	// Socket is the CRI socket the kubelet should be configured to talk
+	// to, or "" if the runtime doesn't require one (Docker's dockershim).
+	Socket() string
+	// KubeletOptions returns the extra --extra-config-style kubelet flags
+	// required to run against this runtime.
+	KubeletOptions() map[string]string
+	// Enable starts the runtime and arranges for it to start on boot.
+	Enable(cr bootstrapper.CommandRunner) error
+	// Disable stops the runtime and prevents it from starting on boot.
+	Disable(cr bootstrapper.CommandRunner) error
+	// LoadImage loads the tarball at path into the runtime's image store,
+	// so a previously cached image is usable without a registry pull.
+	LoadImage(cr bootstrapper.CommandRunner, path string) error
+	// PreflightSetup performs the node-side setup (kernel modules,
+	// sysctls, ...) this runtime needs before kubeadm can start a cluster.
+	PreflightSetup(cr bootstrapper.CommandRunner) error
+	// CgroupDriver returns the cgroup driver ("systemd" or "cgroupfs") this
+	// runtime is configured to use, so callers can check it against the
+	// kubelet's own cgroup driver setting.
+	CgroupDriver(cr bootstrapper.CommandRunner) (string, error)
+}
+
+// Config configures New.
+type Config struct {
+	// Type is the name of the runtime to create: "docker" (the default
+	// when empty), "crio"/"cri-o", "containerd", or "rkt".
+	Type string
+	// Socket overrides the runtime's default CRI socket path.
+	Socket string
+}
+
+// New returns the Runtime named by c.Type.
+func New(c Config) (Runtime, error) {
+	switch c.Type {
+	case "", "docker":
+		return &Docker{socket: c.Socket}, nil
+	case "crio", "cri-o":
+		return &CRIO{socket: c.Socket}, nil
+	case "containerd":
+		return &Containerd{socket: c.Socket}, nil
+	case "rkt":
+		return &Rkt{socket: c.Socket}, nil
+	default:
+		return nil, errors.Errorf("unknown container runtime: %q", c.Type)
+	}
+}
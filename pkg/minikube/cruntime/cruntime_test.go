@@ -0,0 +1,49 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import "testing"
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		runtimeType string
+		wantName    string
+	}{
+		{"", "Docker"},
+		{"docker", "Docker"},
+		{"crio", "CRI-O"},
+		{"cri-o", "CRI-O"},
+		{"containerd", "containerd"},
+		{"rkt", "rkt"},
+	}
+	for _, tt := range tests {
+		r, err := New(Config{Type: tt.runtimeType})
+		if err != nil {
+			t.Errorf("New(%q): %v", tt.runtimeType, err)
+			continue
+		}
+		if r.Name() != tt.wantName {
+			t.Errorf("New(%q).Name() = %q, want %q", tt.runtimeType, r.Name(), tt.wantName)
+		}
+	}
+}
+
+func TestNewUnknownRuntime(t *testing.T) {
+	if _, err := New(Config{Type: "bogus"}); err == nil {
+		t.Error("New(bogus): expected an error, got nil")
+	}
+}
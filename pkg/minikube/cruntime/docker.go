@@ -0,0 +1,85 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/bootstrapper"
+)
+
+// Docker is the default container runtime: Docker driven through the
+// kubelet's built-in dockershim, so it has no CRI socket of its own.
+type Docker struct {
+	socket string
+}
+
+// Name implements Runtime.
+func (r *Docker) Name() string {
+	return "Docker"
+}
+
+// Socket implements Runtime.
+func (r *Docker) Socket() string {
+	return r.socket
+}
+
+// KubeletOptions implements Runtime.
+func (r *Docker) KubeletOptions() map[string]string {
+	return map[string]string{}
+}
+
+// Enable implements Runtime.
+func (r *Docker) Enable(cr bootstrapper.CommandRunner) error {
+	if err := cr.Run("sudo systemctl enable docker && sudo systemctl start docker"); err != nil {
+		return errors.Wrap(err, "enabling docker")
+	}
+	return nil
+}
+
+// Disable implements Runtime.
+func (r *Docker) Disable(cr bootstrapper.CommandRunner) error {
+	if err := cr.Run("sudo systemctl stop docker && sudo systemctl disable docker"); err != nil {
+		return errors.Wrap(err, "disabling docker")
+	}
+	return nil
+}
+
+// LoadImage implements Runtime.
+func (r *Docker) LoadImage(cr bootstrapper.CommandRunner, path string) error {
+	if err := cr.Run(fmt.Sprintf("sudo docker load -i %s", path)); err != nil {
+		return errors.Wrapf(err, "loading image %s into docker", path)
+	}
+	return nil
+}
+
+// PreflightSetup implements Runtime. Docker ships its own bridge and
+// forwarding setup, so there's nothing extra to do here.
+func (r *Docker) PreflightSetup(cr bootstrapper.CommandRunner) error {
+	return nil
+}
+
+// CgroupDriver implements Runtime.
+func (r *Docker) CgroupDriver(cr bootstrapper.CommandRunner) (string, error) {
+	out, err := cr.CombinedOutput(`sudo docker info --format '{{.CgroupDriver}}' 2>/dev/null`)
+	if err != nil {
+		return "", errors.Wrap(err, "getting docker cgroup driver")
+	}
+	return strings.TrimSpace(out), nil
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/bootstrapper"
+)
+
+// commonCRIPreflightSetup loads the bridge netfilter module and enables IP
+// forwarding, the node-side setup every runtime other than Docker needs
+// before kubeadm can bring up pod networking. Docker's own bridge handling
+// already covers this, which is why it isn't shared with Docker.PreflightSetup.
+func commonCRIPreflightSetup(cr bootstrapper.CommandRunner) error {
+	if err := cr.Run("sudo modprobe br_netfilter"); err != nil {
+		return errors.Wrap(err, "modprobe br_netfilter")
+	}
+	if err := cr.Run(`sudo sh -c "echo '1' > /proc/sys/net/ipv4/ip_forward"`); err != nil {
+		return errors.Wrap(err, "setting /proc/sys/net/ipv4/ip_forward")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/bootstrapper"
+)
+
+// Rkt is the rkt container runtime, run through the kubelet's rktnetes
+// integration rather than CRI, so it has no CRI socket.
+type Rkt struct {
+	socket string
+}
+
+// Name implements Runtime.
+func (r *Rkt) Name() string {
+	return "rkt"
+}
+
+// Socket implements Runtime.
+func (r *Rkt) Socket() string {
+	return r.socket
+}
+
+// KubeletOptions implements Runtime.
+func (r *Rkt) KubeletOptions() map[string]string {
+	return map[string]string{
+		"container-runtime": "rkt",
+	}
+}
+
+// Enable implements Runtime.
+func (r *Rkt) Enable(cr bootstrapper.CommandRunner) error {
+	if err := cr.Run("sudo systemctl enable rkt-api && sudo systemctl start rkt-api"); err != nil {
+		return errors.Wrap(err, "enabling rkt-api")
+	}
+	return nil
+}
+
+// Disable implements Runtime.
+func (r *Rkt) Disable(cr bootstrapper.CommandRunner) error {
+	if err := cr.Run("sudo systemctl stop rkt-api && sudo systemctl disable rkt-api"); err != nil {
+		return errors.Wrap(err, "disabling rkt-api")
+	}
+	return nil
+}
+
+// LoadImage implements Runtime. rkt addresses images by content hash rather
+// than a mutable tag, so there's no single-command equivalent of "docker
+// load" for a cached tarball; callers should fetch images through rkt's own
+// image discovery instead of relying on the minikube image cache.
+func (r *Rkt) LoadImage(cr bootstrapper.CommandRunner, path string) error {
+	return errors.New("loading cached images into rkt is not supported")
+}
+
+// PreflightSetup implements Runtime.
+func (r *Rkt) PreflightSetup(cr bootstrapper.CommandRunner) error {
+	return commonCRIPreflightSetup(cr)
+}
+
+// CgroupDriver implements Runtime. rkt doesn't expose a configurable cgroup
+// driver the way the CRI runtimes do.
+func (r *Rkt) CgroupDriver(cr bootstrapper.CommandRunner) (string, error) {
+	return "", errors.New("rkt does not expose a cgroup driver setting")
+}